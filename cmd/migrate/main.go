@@ -0,0 +1,46 @@
+// Command migrate applies (or reverts) the gym service's SQL schema
+// against whatever database store.Open resolves to, without booting the
+// HTTP server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/rshdhere/gym/internal/store"
+	"github.com/rshdhere/gym/internal/store/migrations"
+)
+
+func main() {
+	var down bool
+	var target int64
+
+	flag.BoolVar(&down, "down", false, "revert migrations instead of applying them")
+	flag.Int64Var(&target, "target", int64(migrations.Latest), "schema version to migrate to (defaults to latest for up, zero for down)")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	db, err := store.Open(ctx)
+	if err != nil {
+		slog.Error("open store", "err", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	dir := migrations.Up
+	if down {
+		dir = migrations.Down
+	}
+
+	version, err := db.Migrate(ctx, dir, target)
+	if err != nil {
+		slog.Error("migrate", "err", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("schema now at version %d\n", version)
+}
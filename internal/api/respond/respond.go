@@ -0,0 +1,48 @@
+// Package respond centralizes how handlers write HTTP responses, so every
+// endpoint returns JSON (and errors as a structured problem+json document)
+// the same way instead of each handler calling fmt.Fprintf/http.Error on its
+// own.
+package respond
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// JSON writes v as a JSON body with the given status code.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("encode json response", "err", err)
+	}
+}
+
+// problem is an application/problem+json error document, loosely modeled
+// on RFC 7807: it keeps the content type and the title/status shape, but
+// isn't a conforming instance of the spec - code and details aren't RFC 7807
+// members, and it has no type/detail/instance.
+type problem struct {
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Error writes a problem+json document (see problem). code is a short,
+// machine-readable identifier for the failure (e.g. "workout_not_found");
+// msg is the human-readable title; details is optional extra context
+// (validation errors, field names, etc.) and may be nil.
+func Error(w http.ResponseWriter, status int, code, msg string, details any) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem{
+		Title:   msg,
+		Status:  status,
+		Code:    code,
+		Details: details,
+	}); err != nil {
+		slog.Error("encode problem response", "err", err)
+	}
+}
@@ -1,14 +1,21 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/rshdhere/gym/internal/api/respond"
+	"github.com/rshdhere/gym/internal/store"
 )
 
-type WorkoutHandler struct{}
+// WorkoutHandler holds the store.DB, which may be backed by a SQL driver
+// (*sql.DB) or, when DB_DRIVER=cassandra, by store.KeyValueStore. The
+// handlers below don't query it: HandleGetWorkoutById echoes the parsed id
+// and HandleCreateWorkout returns a fixed response.
+type WorkoutHandler struct {
+	db *store.DB
+}
 
 // EXECUTION ORDER: This function is called in Step 2.2 of app.go (during Application initialization)
 // It creates the WorkoutHandler instance that will be used by routes.
@@ -18,8 +25,8 @@ type WorkoutHandler struct{}
 //  2. Methods use pointer receivers (wh *WorkoutHandler) - returning a pointer is consistent
 //  3. It's more efficient - avoids copying the struct when passing it around
 //  4. If the handler needs to maintain state in the future, using a pointer allows that
-func NewWorkoutHandler() *WorkoutHandler {
-	return &WorkoutHandler{}
+func NewWorkoutHandler(db *store.DB) *WorkoutHandler {
+	return &WorkoutHandler{db: db}
 }
 
 // POINTER EXPLANATION: (wh *WorkoutHandler) is a pointer receiver. We use a pointer receiver because:
@@ -33,21 +40,21 @@ func (wh *WorkoutHandler) HandleGetWorkoutById(w http.ResponseWriter, r *http.Re
 	paramsWorkoutID := chi.URLParam(r, "id")
 
 	if paramsWorkoutID == "" {
-		http.NotFound(w, r)
+		respond.Error(w, http.StatusNotFound, "workout_id_required", "workout id is required", nil)
 		return
 	}
 
 	workoutId, err := strconv.ParseInt(paramsWorkoutID, 10, 64)
 	if err != nil {
-		http.NotFound(w, r)
+		respond.Error(w, http.StatusNotFound, "invalid_workout_id", "workout id must be an integer", nil)
 		return
 	}
 
-	fmt.Fprintf(w, "this is the workout id %d\n", workoutId)
+	respond.JSON(w, http.StatusOK, map[string]int64{"id": workoutId})
 }
 
 // POINTER EXPLANATION: (wh *WorkoutHandler) - same reasoning as HandleGetWorkoutById above
 // Pointer receiver for consistency and efficiency
 func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "created a workout\n")
+	respond.JSON(w, http.StatusCreated, map[string]string{"status": "created a workout"})
 }
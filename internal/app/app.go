@@ -1,30 +1,46 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/rshdhere/gym/internal/api"
+	"github.com/rshdhere/gym/internal/store"
+	"github.com/rshdhere/gym/internal/store/migrations"
 )
 
 type Application struct {
 	Logger         *log.Logger
+	DB             *store.DB
 	WorkoutHandler *api.WorkoutHandler
 }
 
-func NewApplication() (*Application, error) {
+func NewApplication(ctx context.Context) (*Application, error) {
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
-	// our store will go in here
+	db, err := store.Open(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
 
-	// our handlers will go in here
+	if autoMigrate, _ := strconv.ParseBool(os.Getenv(store.AutoMigrateEnvVar)); autoMigrate {
+		version, err := db.Migrate(ctx, migrations.Up, migrations.Latest)
+		if err != nil {
+			return nil, fmt.Errorf("run migrations: %w", err)
+		}
+		slog.Info("migrations applied on startup", "schema_version", version)
+	}
 
-	workoutHandler := api.NewWorkoutHandler()
+	workoutHandler := api.NewWorkoutHandler(db)
 
 	app := &Application{
 		Logger:         logger,
+		DB:             db,
 		WorkoutHandler: workoutHandler,
 	}
 
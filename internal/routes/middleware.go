@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/rshdhere/gym/internal/api/respond"
+)
+
+type requestIDKeyType struct{}
+
+var requestIDKey = requestIDKeyType{}
+
+// requestID tags every request with a UUIDv7 (time-sortable, unlike v4),
+// echoes it in X-Request-Id, and stashes it in the context so downstream
+// handlers and log entries can be correlated with it.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.NewV7()
+		idStr := id.String()
+		if err != nil {
+			// [fallback] extremely unlikely; NewV7 only fails on a broken
+			// clock/entropy source
+			idStr = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-Id", idStr)
+		ctx := context.WithValue(r.Context(), requestIDKey, idStr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the request id requestID stashed in ctx, or
+// "" if none is present (e.g. outside an HTTP request).
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// recoverPanics turns a panicking handler into a 500 problem+json response
+// instead of taking the whole process down, logging the panic with its
+// stack trace and request id for debugging.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				slog.Error("panic recovered",
+					"request_id", RequestIDFromContext(r.Context()),
+					"err", rec,
+					"stack", string(debug.Stack()),
+				)
+				respond.Error(w, http.StatusInternalServerError, "internal_error", "internal server error", nil)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logErrors records a structured slog entry (request id, method, path,
+// status code, remote address) for any response that comes back 4xx/5xx.
+// It doesn't log the problem document's code field or a stack trace.
+func logErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		if ww.Status() >= http.StatusBadRequest {
+			slog.Error("request error",
+				"request_id", RequestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", ww.Status(),
+				"remote_addr", r.RemoteAddr,
+			)
+		}
+	})
+}
@@ -23,6 +23,15 @@ import (
 func SetupRoutes(app *app.Application) *chi.Mux {
 	r := chi.NewRouter()
 
+	// [middleware] request id first so every later middleware/handler can
+	// log it, recover second so a panic anywhere below still gets tagged
+	// and logged, error logging last so it sees the handler's status code
+	// (a panic unwinds past it, so the 500 written by recoverPanics isn't
+	// logged here - recoverPanics logs that case itself)
+	r.Use(requestID)
+	r.Use(recoverPanics)
+	r.Use(logErrors)
+
 	r.Get("/health", app.HealthCheck)
 	r.Get("/workouts/{id}", app.WorkoutHandler.HandleGetWorkoutById)
 
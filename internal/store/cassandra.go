@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gocql/gocql"
+)
+
+// KeyValueStore abstracts the wide-column backends (ScyllaDB/Cassandra) so
+// callers that only need key/value semantics don't have to know about
+// gocql directly.
+type KeyValueStore interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// cassandraStore is the gocql-backed KeyValueStore implementation.
+type cassandraStore struct {
+	session *gocql.Session
+	table   string
+}
+
+// newCassandraStore dials the ScyllaDB/Cassandra cluster described by secret
+// and returns a KeyValueStore backed by it.
+func newCassandraStore(ctx context.Context, secret DBSecret) (KeyValueStore, error) {
+	cluster := gocql.NewCluster(secret.Host)
+	cluster.Keyspace = secret.Keyspace
+	cluster.Consistency = consistencyFromString(secret.Consistency)
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username: secret.Username,
+		Password: secret.Password,
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, fmt.Errorf("connect to cassandra cluster: %w", err)
+	}
+
+	return &cassandraStore{session: session, table: "gym_kv"}, nil
+}
+
+func consistencyFromString(raw string) gocql.Consistency {
+	if raw == "" {
+		return gocql.Quorum
+	}
+	if c, err := gocql.ParseConsistencyWrapper(raw); err == nil {
+		return c
+	}
+	return gocql.Quorum
+}
+
+func (c *cassandraStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	q := c.session.Query(fmt.Sprintf("SELECT value FROM %s WHERE key = ?", c.table), key).WithContext(ctx)
+	if err := q.Scan(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *cassandraStore) Put(ctx context.Context, key string, value []byte) error {
+	q := c.session.Query(fmt.Sprintf("INSERT INTO %s (key, value) VALUES (?, ?)", c.table), key, value).WithContext(ctx)
+	return q.Exec()
+}
+
+func (c *cassandraStore) Delete(ctx context.Context, key string) error {
+	q := c.session.Query(fmt.Sprintf("DELETE FROM %s WHERE key = ?", c.table), key).WithContext(ctx)
+	return q.Exec()
+}
+
+// Ping proves the cluster is reachable without depending on a row existing
+// in c.table - unlike Get, it can't mistake an empty (or not-yet-created)
+// gym_kv table for a down cluster.
+func (c *cassandraStore) Ping(ctx context.Context) error {
+	var clusterName string
+	q := c.session.Query(`SELECT cluster_name FROM system.local`).WithContext(ctx)
+	return q.Scan(&clusterName)
+}
+
+func (c *cassandraStore) Close() {
+	c.session.Close()
+}
@@ -6,16 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
@@ -26,6 +27,10 @@ type DBSecret struct {
 	Database string `json:"dbname"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+
+	// [0.1.1] only populated for the cassandra driver
+	Keyspace    string `json:"keyspace,omitempty"`
+	Consistency string `json:"consistency,omitempty"`
 }
 
 // [0.2] default secret names and env keys
@@ -41,26 +46,43 @@ const (
 	secretNameEnvVar = "DB_SECRET_NAME"
 )
 
-// [0.3] global caches and AWS config singletons
+// [0.3] AWS config singleton. Secret caching itself lives one layer up, in
+// ChainSecretProvider - caching it again here too would let the chain and
+// this package disagree about when a rotated secret is actually noticed.
 var (
-	secretCache   = make(map[string]cachedSecret)
-	secretCacheMu sync.RWMutex
-
 	awsCfg     aws.Config
 	awsCfgOnce sync.Once
 	awsCfgErr  error
 )
 
-// [0.4] cached secret entry with expiry
-type cachedSecret struct {
-	secret    DBSecret
-	expiresAt time.Time
-}
-
 // DB wraps *sql.DB to add helpers.
 // [0.5] wrapper DB type for helper methods
 type DB struct {
-	*sql.DB
+	// [0.5.1] the live *sql.DB handle; an atomic.Pointer so Rotate can swap
+	// it out from under in-flight callers without a lock on every query
+	sqlDB atomic.Pointer[sql.DB]
+
+	// [0.5.2] name of the Driver that built this DB ("postgres", "mysql",
+	// or "cassandra")
+	Driver string
+
+	// [0.5.3] only set when Driver == "cassandra"; sqlDB is nil in that
+	// case since gocql doesn't speak database/sql
+	KV KeyValueStore
+
+	// [0.5.4] rotation support; nil for the cassandra path and for DBs
+	// whose secret comes from local env vars, since there's nothing to
+	// rotate in either case
+	rotator *rotator
+
+	// [0.5.5] SQL dialect migrations.Run should target; empty for cassandra
+	dialect string
+}
+
+// DB returns the current *sql.DB handle. Callers shouldn't cache the
+// result across a rotation; fetch it fresh for each use.
+func (d *DB) DB() *sql.DB {
+	return d.sqlDB.Load()
 }
 
 // [0.6] abstraction for secret retrieval
@@ -68,16 +90,9 @@ type SecretProvider interface {
 	GetSecret(ctx context.Context, name string) (DBSecret, error)
 }
 
-// [0.7] default provider implementation
-type defaultSecretProvider struct{}
-
-func (defaultSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
-	return fetchDBSecret(ctx, name)
-}
-
 func Open(ctx context.Context) (*DB, error) {
-	// [1] delegate to OpenWithProvider
-	return OpenWithProvider(ctx, defaultSecretProvider{})
+	// [1] delegate to OpenWithProvider, trying env/file/Vault/AWS/GCP in turn
+	return OpenWithProvider(ctx, NewSecretProviderFromEnv())
 }
 
 func OpenWithProvider(ctx context.Context, provider SecretProvider) (*DB, error) {
@@ -97,35 +112,96 @@ func OpenWithProvider(ctx context.Context, provider SecretProvider) (*DB, error)
 		return nil, err
 	}
 
+	// [4.1] pick the driver named by DB_DRIVER (defaults to postgres)
+	driverName := getenvDefault(driverEnvVar, "postgres")
+
+	// [4.2] cassandra/scylla speaks KeyValueStore, not database/sql
+	if driverName == "cassandra" {
+		return openCassandra(ctx, env, provider)
+	}
+
+	driver, err := driverFor(driverName)
+	if err != nil {
+		return nil, err
+	}
+
 	// [5] resolve DSN based on environment
-	dsn, secretUsed, err := resolveDSN(ctx, env, provider)
+	dsn, secretUsed, startupSecret, err := resolveDSN(ctx, env, provider, driver)
 	if err != nil {
 		return nil, err
 	}
 
 	// [6] open database connection with retry
-	db, err := openWithRetry(ctx, dsn)
+	db, err := openWithRetry(ctx, sqlDriverName(driver.Name()), dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	// [6.1] run driver-specific setup (session pragmas, etc.)
+	if err := driver.Register(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("register %s driver: %w", driver.Name(), err)
+	}
+
 	// [7] configure connection pooling
 	configureConnectionPool(db)
 
 	// [8] log successful connection
 	slog.Info("Connected to Database, where",
 		"env", env,
+		"driver", driver.Name(),
 		"secret", secretUsed,
 		"APP_ENV", rawEnv,
 	)
 
-	return &DB{DB: db}, nil
+	wrapped := &DB{Driver: driver.Name(), dialect: driver.MigrationsDialect()}
+	wrapped.sqlDB.Store(db)
+
+	// [8.1] local env vars aren't rotated, so there's nothing to watch
+	if env != localEnvVal {
+		wrapped.rotator = startRotator(wrapped, provider, driver, secretUsed, startupSecret)
+	}
+
+	return wrapped, nil
+}
+
+func openCassandra(ctx context.Context, env string, provider SecretProvider) (*DB, error) {
+	if env == localEnvVal {
+		return nil, fmt.Errorf("cassandra driver requires a secret-managed environment, got %q", env)
+	}
+
+	secretName, err := selectSecretName(env)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := provider.GetSecret(ctx, secretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load database secret: %w", err)
+	}
+
+	kv, err := newCassandraStore(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Connected to Database, where",
+		"env", env,
+		"driver", "cassandra",
+		"secret", secretName,
+	)
+
+	return &DB{Driver: "cassandra", KV: kv}, nil
 }
 
-func resolveDSN(ctx context.Context, env string, provider SecretProvider) (string, string, error) {
+// resolveDSN also returns the DBSecret it resolved (zero value on the local
+// path, which doesn't fetch one) so Open can hand the rotator the exact
+// secret the live pool was built from, instead of the rotator re-fetching
+// and possibly seeding itself from a secret that's already rotated.
+func resolveDSN(ctx context.Context, env string, provider SecretProvider, driver Driver) (string, string, DBSecret, error) {
 	if env == localEnvVal {
 		// [5.1] (local path) build DSN from local env vars
-		return localDSNFromEnv(), "local-env-vars", nil
+		return localDSNFromEnv(driver), "local-env-vars", DBSecret{}, nil
 	}
 
 	// [5.2] (non-local path) build DSN from secret-managed credentials
@@ -135,34 +211,26 @@ func resolveDSN(ctx context.Context, env string, provider SecretProvider) (strin
 	// [5.2.2] pick secret name for environment
 	secretName, err := selectSecretName(env)
 	if err != nil {
-		return "", "", err
+		return "", "", DBSecret{}, err
 	}
 
 	// [5.2.3] fetch database secret
 	secret, err := provider.GetSecret(ctx, secretName)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to load database secret: %w", err)
-	}
-
-	// [5.2.4] format DSN from secret values
-	return fmt.Sprintf(
-		"postgresql://%s:%s@%s:%s/%s?sslmode=%s",
-		url.QueryEscape(secret.Username),
-		url.QueryEscape(secret.Password),
-		secret.Host,
-		secret.Port,
-		secret.Database,
-		sslMode,
-	), secretName, nil
+		return "", "", DBSecret{}, fmt.Errorf("failed to load database secret: %w", err)
+	}
+
+	// [5.2.4] format DSN from secret values via the selected driver
+	return driver.DSN(secret, DriverOptions{SSLMode: sslMode}), secretName, secret, nil
 }
 
-func openWithRetry(ctx context.Context, dsn string) (*sql.DB, error) {
+func openWithRetry(ctx context.Context, sqlDriver, dsn string) (*sql.DB, error) {
 	var db *sql.DB
 	var err error
 
 	// [6.1] attempt (i+1) to open and ping with exponential backoff
 	for i := 0; i < 3; i++ {
-		db, err = sql.Open("pgx", dsn)
+		db, err = sql.Open(sqlDriver, dsn)
 		if err == nil {
 			if err = db.PingContext(ctx); err == nil {
 				return db, nil
@@ -179,20 +247,31 @@ func openWithRetry(ctx context.Context, dsn string) (*sql.DB, error) {
 	return nil, fmt.Errorf("failed to open database after retries: %w", err)
 }
 
-func localDSNFromEnv() string {
-	// [5.1.1] read local connection env vars
+func localDSNFromEnv(driver Driver) string {
+	// [5.1.1] read local connection env vars, defaulting to whichever
+	// ports/users the chosen driver's dev compose file expects
+	defaultPort := "5432"
+	defaultUser := "postgres"
+	if driver.Name() == "mysql" {
+		defaultPort = "3306"
+		defaultUser = "root"
+	}
+
 	host := getenvDefault("POSTGRES_HOST", "localhost")
-	port := getenvDefault("POSTGRES_PORT", "5432")
-	user := getenvDefault("POSTGRES_USER", "postgres")
+	port := getenvDefault("POSTGRES_PORT", defaultPort)
+	user := getenvDefault("POSTGRES_USER", defaultUser)
 	pass := getenvDefault("POSTGRES_PASSWORD", "postgres")
 	db := getenvDefault("POSTGRES_DB", "postgres")
 	sslMode := getenvDefault("DB_SSL_MODE", "disable")
 
-	// [5.1.2] format local DSN
-	return fmt.Sprintf(
-		"postgresql://%s:%s@%s:%s/%s?sslmode=%s",
-		user, pass, host, port, db, sslMode,
-	)
+	// [5.1.2] format local DSN via the selected driver
+	return driver.DSN(DBSecret{
+		Host:     host,
+		Port:     port,
+		Username: user,
+		Password: pass,
+		Database: db,
+	}, DriverOptions{SSLMode: sslMode})
 }
 
 // [0.8] helper: env with default (string)
@@ -297,15 +376,15 @@ func awsConfig(ctx context.Context) (aws.Config, error) {
 }
 
 func fetchDBSecret(ctx context.Context, name string) (DBSecret, error) {
-	// [5.2.3.1] check cached secret
-	if cached, ok := loadCachedSecret(name); ok {
-		return cached, nil
-	}
+	secret, _, err := fetchDBSecretWithVersion(ctx, name)
+	return secret, err
+}
 
+func fetchDBSecretWithVersion(ctx context.Context, name string) (DBSecret, string, error) {
 	// [5.2.3.2] load AWS configuration
 	cfg, err := awsConfig(ctx)
 	if err != nil {
-		return DBSecret{}, fmt.Errorf("load aws config: %w", err)
+		return DBSecret{}, "", fmt.Errorf("load aws config: %w", err)
 	}
 
 	// [5.2.3.3] initialize secrets manager client
@@ -318,53 +397,25 @@ func fetchDBSecret(ctx context.Context, name string) (DBSecret, error) {
 	// [5.2.3.4] fetch secret value from AWS
 	result, err := svc.GetSecretValue(ctx, input)
 	if err != nil {
-		return DBSecret{}, fmt.Errorf("get secret value: %w", err)
+		return DBSecret{}, "", fmt.Errorf("get secret value: %w", err)
 	}
 
 	if result.SecretString == nil {
-		return DBSecret{}, fmt.Errorf("secret value missing string payload")
+		return DBSecret{}, "", fmt.Errorf("secret value missing string payload")
 	}
 
 	// [5.2.3.5] decode secret JSON payload
 	var secret DBSecret
 	if err := json.Unmarshal([]byte(*result.SecretString), &secret); err != nil {
-		return DBSecret{}, fmt.Errorf("decode secret json: %w", err)
+		return DBSecret{}, "", fmt.Errorf("decode secret json: %w", err)
 	}
 
-	// [5.2.3.6] cache decoded secret for reuse
-	storeCachedSecret(name, secret)
-
-	return secret, nil
-}
-
-func loadCachedSecret(name string) (DBSecret, bool) {
-	// [5.2.3.1.1] acquire cache lock
-	secretCacheMu.Lock()
-	entry, ok := secretCache[name]
-	defer secretCacheMu.Unlock()
-	if !ok {
-		// [5.2.3.1.2] no cache entry found
-		return DBSecret{}, false
-	}
-
-	if time.Now().After(entry.expiresAt) {
-		// [5.2.3.1.3] evict expired entry
-		delete(secretCache, name)
-		return DBSecret{}, false
+	var version string
+	if result.VersionId != nil {
+		version = *result.VersionId
 	}
 
-	// [5.2.3.1.4] return valid cached secret
-	return entry.secret, true
-}
-
-func storeCachedSecret(name string, secret DBSecret) {
-	// [5.2.3.6.1] store secret with TTL
-	secretCacheMu.Lock()
-	secretCache[name] = cachedSecret{
-		secret:    secret,
-		expiresAt: time.Now().Add(getSecretCacheTTL()),
-	}
-	secretCacheMu.Unlock()
+	return secret, version, nil
 }
 
 func getSecretCacheTTL() time.Duration {
@@ -389,9 +440,30 @@ func configureConnectionPool(db *sql.DB) {
 	db.SetConnMaxLifetime(connLifetime)
 }
 
+// Close releases the underlying connection, whichever backend it is.
+// [9.0] stops rotation (if any) before closing the live handle
+func (db *DB) Close() error {
+	if db.rotator != nil {
+		db.rotator.stop()
+	}
+
+	if db.Driver == "cassandra" {
+		db.KV.Close()
+		return nil
+	}
+	return db.DB().Close()
+}
+
 // HealthCheck pings the database with a short timeout.
 // [9] optional health check call path
 func (db *DB) HealthCheck(ctx context.Context) error {
+	if db.Driver == "cassandra" {
+		// [9.1] query system.local instead of reading a key - gym_kv isn't
+		// created by any migration, so a Get against it reports a perfectly
+		// healthy cluster as down just because the row doesn't exist yet
+		return db.KV.Ping(ctx)
+	}
+
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -399,5 +471,15 @@ func (db *DB) HealthCheck(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	return db.PingContext(ctx)
+	return db.DB().PingContext(ctx)
+}
+
+// Rotate manually re-checks the backing secret and, if it changed, swaps in
+// a freshly-built connection pool. Useful from an admin endpoint to force a
+// rotation instead of waiting for the background rotator's next tick.
+func (db *DB) Rotate(ctx context.Context) error {
+	if db.rotator == nil {
+		return fmt.Errorf("rotation not enabled for this DB (local env or cassandra driver)")
+	}
+	return db.rotator.rotate(ctx)
 }
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+)
+
+// gcpSecretProvider fetches secrets from GCP Secret Manager, for teams
+// running the gym service on GKE/Cloud Run instead of AWS.
+type gcpSecretProvider struct {
+	project string
+}
+
+func (g gcpSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	client, err := secretmanager.NewClient(ctx)
+	if err != nil {
+		return DBSecret{}, fmt.Errorf("create gcp secret manager client: %w", err)
+	}
+	defer client.Close()
+
+	req := &secretmanagerpb.AccessSecretVersionRequest{
+		Name: fmt.Sprintf("projects/%s/secrets/%s/versions/latest", g.project, name),
+	}
+
+	result, err := client.AccessSecretVersion(ctx, req)
+	if err != nil {
+		return DBSecret{}, fmt.Errorf("access gcp secret %s: %w", name, err)
+	}
+
+	var secret DBSecret
+	if err := json.Unmarshal(result.Payload.Data, &secret); err != nil {
+		return DBSecret{}, fmt.Errorf("decode gcp secret %s: %w", name, err)
+	}
+
+	return secret, nil
+}
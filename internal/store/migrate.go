@@ -0,0 +1,23 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rshdhere/gym/internal/store/migrations"
+)
+
+// AutoMigrateEnvVar, when truthy, makes app.NewApplication run pending
+// migrations up to the latest version on startup.
+const AutoMigrateEnvVar = "DB_AUTO_MIGRATE"
+
+// Migrate applies the embedded SQL migrations in dir up to targetVersion
+// (migrations.Latest for "all pending") and returns the schema version left
+// in place.
+func (d *DB) Migrate(ctx context.Context, dir migrations.Direction, targetVersion int64) (int64, error) {
+	if d.Driver == "cassandra" {
+		return 0, fmt.Errorf("migrations are not supported for the cassandra driver")
+	}
+
+	return migrations.Run(ctx, d.DB(), d.dialect, dir, targetVersion)
+}
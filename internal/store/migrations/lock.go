@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// lockName is hashed/keyed per dialect so concurrent pods running
+// Migrate at the same time serialize instead of racing on the same schema.
+const lockName = "gym_migrations"
+
+// acquireLock takes a session-scoped advisory lock on conn so only one
+// process applies migrations at a time, and returns a func that releases
+// it. pg_advisory_lock/GET_LOCK are tied to the connection they're taken
+// on, so the caller must run every later migration statement - and the
+// eventual unlock - on this same *sql.Conn, never back on the pool.
+func acquireLock(ctx context.Context, conn *sql.Conn, dialect string) (func(), error) {
+	switch dialect {
+	case "postgres":
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext($1))`, lockName); err != nil {
+			return nil, fmt.Errorf("pg_advisory_lock: %w", err)
+		}
+		return func() {
+			conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext($1))`, lockName)
+		}, nil
+
+	case "mysql":
+		var acquired int
+		row := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 10)`, lockName)
+		if err := row.Scan(&acquired); err != nil {
+			return nil, fmt.Errorf("GET_LOCK: %w", err)
+		}
+		if acquired != 1 {
+			return nil, fmt.Errorf("GET_LOCK(%s) timed out", lockName)
+		}
+		return func() {
+			conn.ExecContext(ctx, `SELECT RELEASE_LOCK(?)`, lockName)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("no advisory lock support for dialect %q", dialect)
+	}
+}
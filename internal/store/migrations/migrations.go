@@ -0,0 +1,344 @@
+// Package migrations applies the embedded SQL files in ./migrations
+// against whichever database store.Open connected to, tracking what's
+// already been applied in a schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/postgres/*.sql migrations/mysql/*.sql
+var sqlFiles embed.FS
+
+// Direction selects which half of a migration pair to apply.
+type Direction int
+
+const (
+	Up Direction = iota
+	Down
+)
+
+// Latest means "apply every migration newer than the current version"
+// when passed as Migrate's targetVersion with Up; with Down it means
+// "revert every applied migration" (target version 0).
+const Latest int64 = -1
+
+// migration pairs a version's up and down SQL, read from
+// <version>_<name>.up.sql / .down.sql.
+type migration struct {
+	version int64
+	name    string
+	upSQL   string
+	downSQL string
+}
+
+// load reads the migration pairs shipped for dialect. Each dialect keeps its
+// own SQL under migrations/<dialect> because the DDL isn't portable (e.g.
+// Postgres's BIGSERIAL/TIMESTAMPTZ vs MySQL's AUTO_INCREMENT/TIMESTAMP).
+func load(dialect string) ([]migration, error) {
+	dir := "migrations/" + dialect
+
+	entries, err := fs.ReadDir(sqlFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations shipped for dialect %q: %w", dialect, err)
+	}
+
+	byVersion := map[int64]*migration{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		isUp := strings.HasSuffix(name, ".up.sql")
+		isDown := strings.HasSuffix(name, ".down.sql")
+		if !isUp && !isDown {
+			continue
+		}
+
+		version, base, err := parseFilename(name)
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := sqlFiles.ReadFile(dir + "/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", name, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: base}
+			byVersion[version] = m
+		}
+
+		if isUp {
+			m.upSQL = string(contents)
+		} else {
+			m.downSQL = string(contents)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.upSQL == "" || m.downSQL == "" {
+			return nil, fmt.Errorf("migration %d is missing its up or down file", m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
+
+// parseFilename splits "0001_create_workouts.up.sql" into version 1 and
+// base name "create_workouts".
+func parseFilename(name string) (int64, string, error) {
+	stem := strings.TrimSuffix(strings.TrimSuffix(name, ".up.sql"), ".down.sql")
+
+	parts := strings.SplitN(stem, "_", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("migration file %q doesn't match <version>_<name> convention", name)
+	}
+
+	version, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("migration file %q has a non-numeric version: %w", name, err)
+	}
+
+	return version, parts[1], nil
+}
+
+// Run applies migrations in dir up to targetVersion (Latest for "all of
+// them") against db, using dialect to pick the schema_migrations DDL and
+// advisory lock statements. It returns the version left in place once it
+// finishes.
+//
+// Everything here - the advisory lock, the schema_migrations reads/writes,
+// every migration statement, and the eventual unlock - runs on a single
+// pinned *sql.Conn pulled from db. pg_advisory_lock/GET_LOCK are scoped to
+// the connection that took them; running any of this on other connections
+// from the pool would make the unlock silently no-op and leave the lock
+// held until the connection is reaped by ConnMaxLifetime.
+func Run(ctx context.Context, db *sql.DB, dialect string, dir Direction, targetVersion int64) (int64, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("pin connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	unlock, err := acquireLock(ctx, conn, dialect)
+	if err != nil {
+		return 0, fmt.Errorf("acquire migrations lock: %w", err)
+	}
+	defer unlock()
+
+	if err := ensureSchemaMigrationsTable(ctx, conn, dialect); err != nil {
+		return 0, err
+	}
+
+	current, dirty, err := currentVersion(ctx, conn, dialect)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return current, fmt.Errorf("schema_migrations is marked dirty at version %d; fix manually before migrating", current)
+	}
+
+	switch dir {
+	case Up:
+		return applyUp(ctx, conn, dialect, migrations, current, targetVersion)
+	case Down:
+		return applyDown(ctx, conn, dialect, migrations, current, targetVersion)
+	default:
+		return current, fmt.Errorf("unknown migration direction %d", dir)
+	}
+}
+
+func applyUp(ctx context.Context, conn *sql.Conn, dialect string, migrations []migration, current, target int64) (int64, error) {
+	applied := current
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if target != Latest && m.version > target {
+			break
+		}
+
+		if err := applyOne(ctx, conn, dialect, m.version, m.name, m.upSQL); err != nil {
+			return applied, err
+		}
+		applied = m.version
+
+		slog.Info("applied migration", "version", m.version, "name", m.name, "direction", "up")
+	}
+
+	return applied, nil
+}
+
+func applyDown(ctx context.Context, conn *sql.Conn, dialect string, migrations []migration, current, target int64) (int64, error) {
+	if target == Latest {
+		target = 0
+	}
+
+	applied := current
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version > current || m.version <= target {
+			continue
+		}
+
+		if err := applyOneDown(ctx, conn, dialect, m.version, m.name, m.downSQL); err != nil {
+			return applied, err
+		}
+		applied = m.version - 1
+
+		slog.Info("applied migration", "version", m.version, "name", m.name, "direction", "down")
+	}
+
+	return applied, nil
+}
+
+// applyOne marks version dirty, runs its up SQL, then clears the dirty
+// flag. The dirty row is written and cleared as two separate statements -
+// not wrapped in one transaction around the migration SQL - because MySQL
+// DDL causes an implicit commit partway through a transaction; if stmt
+// fails or the process dies mid-migration, the dirty row left behind is
+// the only record that schema_migrations needs manual attention.
+func applyOne(ctx context.Context, conn *sql.Conn, dialect string, version int64, name, stmt string) error {
+	if err := insertDirty(ctx, conn, dialect, version); err != nil {
+		return fmt.Errorf("mark migration %d dirty: %w", version, err)
+	}
+
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("run migration %d (%s): %w (schema_migrations left dirty at this version; fix manually)", version, name, err)
+	}
+
+	if err := clearDirty(ctx, conn, dialect, version); err != nil {
+		return fmt.Errorf("clear dirty flag for migration %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// applyOneDown is applyOne's mirror for reverting a migration: mark the
+// already-applied row dirty, run the down SQL, then remove the row.
+func applyOneDown(ctx context.Context, conn *sql.Conn, dialect string, version int64, name, stmt string) error {
+	if err := markDirty(ctx, conn, dialect, version); err != nil {
+		return fmt.Errorf("mark migration %d dirty: %w", version, err)
+	}
+
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("run migration %d (%s) down: %w (schema_migrations left dirty at this version; fix manually)", version, name, err)
+	}
+
+	if err := removeVersion(ctx, conn, dialect, version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// placeholder returns the nth bind-parameter marker for dialect, since
+// pgx wants "$1, $2, ..." while go-sql-driver/mysql wants "?".
+func placeholder(dialect string, n int) string {
+	if dialect == "postgres" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func ensureSchemaMigrationsTable(ctx context.Context, conn *sql.Conn, dialect string) error {
+	ddl := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE,
+		applied_at TIMESTAMP NOT NULL
+	)`
+
+	if dialect == "postgres" {
+		ddl = strings.Replace(ddl, "TIMESTAMP NOT NULL", "TIMESTAMPTZ NOT NULL", 1)
+	}
+
+	_, err := conn.ExecContext(ctx, ddl)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn, dialect string) (int64, bool, error) {
+	var version sql.NullInt64
+	var dirty sql.NullBool
+
+	row := conn.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`)
+	if err := row.Scan(&version); err != nil {
+		return 0, false, fmt.Errorf("read current schema version: %w", err)
+	}
+	if !version.Valid {
+		return 0, false, nil
+	}
+
+	query := fmt.Sprintf(`SELECT dirty FROM schema_migrations WHERE version = %s`, placeholder(dialect, 1))
+	row = conn.QueryRowContext(ctx, query, version.Int64)
+	if err := row.Scan(&dirty); err != nil {
+		return version.Int64, false, fmt.Errorf("read dirty flag for version %d: %w", version.Int64, err)
+	}
+
+	return version.Int64, dirty.Bool, nil
+}
+
+// insertDirty records a new version as applied-but-dirty, before its SQL
+// runs, so a crash or failure mid-migration leaves a trail.
+func insertDirty(ctx context.Context, conn *sql.Conn, dialect string, version int64) error {
+	query := fmt.Sprintf(
+		`INSERT INTO schema_migrations (version, dirty, applied_at) VALUES (%s, TRUE, %s)`,
+		placeholder(dialect, 1), placeholder(dialect, 2),
+	)
+	if _, err := conn.ExecContext(ctx, query, version, time.Now().UTC()); err != nil {
+		return fmt.Errorf("record migration version %d: %w", version, err)
+	}
+	return nil
+}
+
+// clearDirty flips an already-recorded version back to clean once its SQL
+// has run successfully.
+func clearDirty(ctx context.Context, conn *sql.Conn, dialect string, version int64) error {
+	query := fmt.Sprintf(`UPDATE schema_migrations SET dirty = FALSE WHERE version = %s`, placeholder(dialect, 1))
+	if _, err := conn.ExecContext(ctx, query, version); err != nil {
+		return fmt.Errorf("clear dirty flag for version %d: %w", version, err)
+	}
+	return nil
+}
+
+// markDirty flags an existing version dirty before its down SQL runs, so a
+// failed revert is distinguishable from a clean, already-reverted state.
+func markDirty(ctx context.Context, conn *sql.Conn, dialect string, version int64) error {
+	query := fmt.Sprintf(`UPDATE schema_migrations SET dirty = TRUE WHERE version = %s`, placeholder(dialect, 1))
+	if _, err := conn.ExecContext(ctx, query, version); err != nil {
+		return fmt.Errorf("mark version %d dirty: %w", version, err)
+	}
+	return nil
+}
+
+func removeVersion(ctx context.Context, conn *sql.Conn, dialect string, version int64) error {
+	query := fmt.Sprintf(`DELETE FROM schema_migrations WHERE version = %s`, placeholder(dialect, 1))
+	if _, err := conn.ExecContext(ctx, query, version); err != nil {
+		return fmt.Errorf("remove migration version %d: %w", version, err)
+	}
+	return nil
+}
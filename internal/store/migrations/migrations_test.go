@@ -0,0 +1,220 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDB is the in-memory state behind fakeDriver: whether the advisory
+// lock is held, and the schema_migrations rows (version -> dirty). It only
+// understands the handful of statements migrations.go actually issues
+// against the "mysql" dialect (GET_LOCK/RELEASE_LOCK, "?" placeholders);
+// every other Exec - including a migration's own up/down SQL - is accepted
+// as a no-op so it "runs" without a real database, while any other query
+// returns an error.
+type fakeDB struct {
+	locked bool
+	rows   map[int64]bool
+}
+
+type fakeDriver struct{ db *fakeDB }
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakeConn: Prepare not supported (query %q)", query)
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("fakeConn: transactions not supported")
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+		c.db.locked = false
+	case strings.Contains(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		if c.db.rows == nil {
+			c.db.rows = map[int64]bool{}
+		}
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		c.db.rows[argInt64(args[0])] = true
+	case strings.Contains(query, "SET dirty = FALSE"):
+		c.db.rows[argInt64(args[0])] = false
+	case strings.Contains(query, "SET dirty = TRUE"):
+		c.db.rows[argInt64(args[0])] = true
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		delete(c.db.rows, argInt64(args[0]))
+	}
+	return driver.ResultNoRows, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		if c.db.locked {
+			return &singleRow{val: int64(0)}, nil
+		}
+		c.db.locked = true
+		return &singleRow{val: int64(1)}, nil
+
+	case strings.HasPrefix(query, "SELECT MAX(version)"):
+		var max int64
+		found := false
+		for v := range c.db.rows {
+			if !found || v > max {
+				max, found = v, true
+			}
+		}
+		if !found {
+			return &singleRow{val: nil}, nil
+		}
+		return &singleRow{val: max}, nil
+
+	case strings.HasPrefix(query, "SELECT dirty FROM schema_migrations"):
+		return &singleRow{val: c.db.rows[argInt64(args[0])]}, nil
+
+	default:
+		return nil, fmt.Errorf("fakeConn: unsupported query %q", query)
+	}
+}
+
+func argInt64(v driver.NamedValue) int64 {
+	switch n := v.Value.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	default:
+		panic(fmt.Sprintf("fakeConn: unexpected arg type %T", v.Value))
+	}
+}
+
+// singleRow is a one-row, one-column driver.Rows, which is all Run's
+// queries ever ask for.
+type singleRow struct {
+	val  any
+	read bool
+}
+
+func (r *singleRow) Columns() []string { return []string{"value"} }
+func (r *singleRow) Close() error      { return nil }
+func (r *singleRow) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = r.val
+	return nil
+}
+
+var driverSeq int64
+
+// openFakeDB registers a fresh fakeDriver instance and opens a *sql.DB
+// against it. Each test gets its own fakeDB so they can't see each other's
+// schema_migrations state.
+func openFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	name := fmt.Sprintf("fakemigrations_%d", atomic.AddInt64(&driverSeq, 1))
+	sql.Register(name, fakeDriver{db: &fakeDB{}})
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRunUpAppliesMigrationsAndIsIdempotent(t *testing.T) {
+	db := openFakeDB(t)
+	ctx := context.Background()
+
+	version, err := Run(ctx, db, "mysql", Up, Latest)
+	if err != nil {
+		t.Fatalf("Run(Up) returned error: %v", err)
+	}
+	if version != 1 {
+		t.Fatalf("Run(Up) version = %d, want 1", version)
+	}
+
+	// running again with nothing new to apply should be a no-op, not an error
+	version, err = Run(ctx, db, "mysql", Up, Latest)
+	if err != nil {
+		t.Fatalf("second Run(Up) returned error: %v", err)
+	}
+	if version != 1 {
+		t.Fatalf("second Run(Up) version = %d, want 1", version)
+	}
+}
+
+func TestRunDownRevertsMigrations(t *testing.T) {
+	db := openFakeDB(t)
+	ctx := context.Background()
+
+	if _, err := Run(ctx, db, "mysql", Up, Latest); err != nil {
+		t.Fatalf("Run(Up) returned error: %v", err)
+	}
+
+	version, err := Run(ctx, db, "mysql", Down, Latest)
+	if err != nil {
+		t.Fatalf("Run(Down) returned error: %v", err)
+	}
+	if version != 0 {
+		t.Fatalf("Run(Down) version = %d, want 0", version)
+	}
+}
+
+func TestRunBlocksWhenSchemaIsDirty(t *testing.T) {
+	db := openFakeDB(t)
+	ctx := context.Background()
+
+	if err := ensureSchemaMigrationsTable(ctx, mustConn(t, db), "mysql"); err != nil {
+		t.Fatalf("ensureSchemaMigrationsTable: %v", err)
+	}
+	if err := insertDirty(ctx, mustConn(t, db), "mysql", 1); err != nil {
+		t.Fatalf("insertDirty: %v", err)
+	}
+
+	if _, err := Run(ctx, db, "mysql", Up, Latest); err == nil {
+		t.Fatal("Run(Up) succeeded against a dirty schema_migrations row, want error")
+	}
+}
+
+func mustConn(t *testing.T, db *sql.DB) *sql.Conn {
+	t.Helper()
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("pin connection: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestLoadReadsEveryShippedDialect(t *testing.T) {
+	for _, dialect := range []string{"postgres", "mysql"} {
+		migrations, err := load(dialect)
+		if err != nil {
+			t.Fatalf("load(%q): %v", dialect, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("load(%q) returned no migrations", dialect)
+		}
+		for _, m := range migrations {
+			if m.upSQL == "" || m.downSQL == "" {
+				t.Fatalf("load(%q) migration %d missing up or down SQL", dialect, m.version)
+			}
+		}
+	}
+}
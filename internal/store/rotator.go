@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// versionedSecretProvider is implemented by providers that can report which
+// secret version a value came from (awsSecretProvider, and ChainSecretProvider,
+// which passes through the version of whichever provider answered). The
+// rotator type-asserts for it so rotation events can log the version; when
+// a provider doesn't support it, rotation still works, just without a
+// version in the logs.
+type versionedSecretProvider interface {
+	GetSecretVersion(ctx context.Context, name string) (DBSecret, string, error)
+}
+
+// rotator periodically re-fetches the secret behind a DB and, if the
+// credentials or host changed, opens a new connection pool and swaps it in
+// behind DB.sqlDB without interrupting in-flight callers.
+type rotator struct {
+	db         *DB
+	provider   SecretProvider
+	driver     Driver
+	secretName string
+
+	mu         sync.Mutex
+	lastSecret DBSecret
+	seeded     bool
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// startRotator seeds the rotator with startupSecret - the exact DBSecret
+// Open already fetched to build the live pool - so the first tick compares
+// against what's actually in use instead of re-fetching and risking a
+// rotation that happened during DB_SECRET_CACHE_TTL becoming the baseline,
+// which would permanently hide that very rotation. It then launches the
+// background loop.
+func startRotator(db *DB, provider SecretProvider, driver Driver, secretName string, startupSecret DBSecret) *rotator {
+	r := &rotator{
+		db:         db,
+		provider:   provider,
+		driver:     driver,
+		secretName: secretName,
+		lastSecret: startupSecret,
+		seeded:     true,
+		stopCh:     make(chan struct{}),
+	}
+
+	go r.loop()
+	return r
+}
+
+func (r *rotator) loop() {
+	interval := getSecretCacheTTL()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			if err := r.rotate(context.Background()); err != nil {
+				slog.Warn("secret rotation check failed", "secret", r.secretName, "err", err)
+			}
+		}
+	}
+}
+
+func (r *rotator) stop() {
+	r.stopOnce.Do(func() { close(r.stopCh) })
+}
+
+func (r *rotator) fetchSecret(ctx context.Context) (DBSecret, string, error) {
+	return fetchSecretAndVersion(ctx, r.provider, r.secretName)
+}
+
+// rotate checks the current secret against the one the live pool was built
+// from and, if the username/password/host changed, swaps in a new pool.
+// It's safe to call concurrently with the background loop (e.g. from a
+// manual DB.Rotate trigger); only one swap happens per genuine change.
+func (r *rotator) rotate(ctx context.Context) error {
+	secret, version, err := r.fetchSecret(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch secret for rotation: %w", err)
+	}
+
+	r.mu.Lock()
+	prev := r.lastSecret
+	firstRead := !r.seeded
+	r.lastSecret = secret
+	r.seeded = true
+	r.mu.Unlock()
+
+	if firstRead {
+		return nil
+	}
+
+	// Only Username/Password/Host are watched - a secret that moves Port or
+	// renames Database without also touching one of these three won't
+	// trigger a swap, and the pool keeps hitting the old endpoint.
+	if prev.Username == secret.Username && prev.Password == secret.Password && prev.Host == secret.Host {
+		return nil
+	}
+
+	if err := r.swap(ctx, secret, version); err != nil {
+		// the live pool is still built from prev, so restore it as the
+		// baseline and let the next check retry the swap
+		r.mu.Lock()
+		if r.lastSecret == secret {
+			r.lastSecret = prev
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (r *rotator) swap(ctx context.Context, secret DBSecret, version string) error {
+	sslMode := getenvDefault("DB_SSL_MODE", "require")
+	dsn := r.driver.DSN(secret, DriverOptions{SSLMode: sslMode})
+
+	newPool, err := openWithRetry(ctx, sqlDriverName(r.driver.Name()), dsn)
+	if err != nil {
+		slog.Error("secret rotation failed to open new pool", "secret", r.secretName, "version", version, "err", err)
+		return err
+	}
+
+	if err := r.driver.Register(newPool); err != nil {
+		newPool.Close()
+		slog.Error("secret rotation failed driver register", "secret", r.secretName, "version", version, "err", err)
+		return err
+	}
+
+	configureConnectionPool(newPool)
+
+	oldPool := r.db.sqlDB.Swap(newPool)
+
+	slog.Info("rotated database secret", "secret", r.secretName, "version", version)
+
+	if oldPool != nil {
+		go drainPool(oldPool)
+	}
+
+	return nil
+}
+
+// drainPool gives in-flight queries on the old pool a grace period to
+// finish, then forces idle connections closed and shuts the pool down.
+func drainPool(old *sql.DB) {
+	grace := getenvDuration("DB_ROTATE_DRAIN_GRACE", 30*time.Second)
+	time.Sleep(grace)
+
+	old.SetConnMaxIdleTime(0)
+	if err := old.Close(); err != nil {
+		slog.Warn("error closing drained connection pool", "err", err)
+	}
+}
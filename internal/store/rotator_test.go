@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeSwapConn is a no-op driver.Conn - rotate()'s swap only needs to open
+// and ping a pool, not run any real queries against it.
+type fakeSwapConn struct{}
+
+func (fakeSwapConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeSwapConn: Prepare not supported")
+}
+func (fakeSwapConn) Close() error              { return nil }
+func (fakeSwapConn) Begin() (driver.Tx, error) { return nil, errors.New("fakeSwapConn: transactions not supported") }
+
+type fakeSwapDriver struct{}
+
+func (fakeSwapDriver) Open(name string) (driver.Conn, error) { return fakeSwapConn{}, nil }
+
+var fakeSwapDriverSeq int64
+
+// registerFakeSwapDriver registers a fresh database/sql driver and returns
+// the name it's registered under, so each test gets an isolated pool space.
+func registerFakeSwapDriver(t *testing.T) string {
+	t.Helper()
+	name := fmt.Sprintf("fakeswap_%d", atomic.AddInt64(&fakeSwapDriverSeq, 1))
+	sql.Register(name, fakeSwapDriver{})
+	return name
+}
+
+// stubDriver is a Driver whose Name() matches whatever database/sql driver
+// name it's given, so rotate's swap can open a real (if fake) *sql.DB.
+type stubDriver struct{ name string }
+
+func (d stubDriver) Name() string                                   { return d.name }
+func (d stubDriver) DSN(secret DBSecret, opts DriverOptions) string { return "fake-dsn" }
+func (d stubDriver) Register(db *sql.DB) error                     { return nil }
+func (d stubDriver) MigrationsDialect() string                     { return "postgres" }
+
+// stubSecretProvider returns secrets from a canned sequence, one per call,
+// so tests can simulate a rotation happening on a specific check.
+type stubSecretProvider struct {
+	secrets []DBSecret
+	calls   int
+}
+
+func (p *stubSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	i := p.calls
+	if i >= len(p.secrets) {
+		i = len(p.secrets) - 1
+	}
+	p.calls++
+	return p.secrets[i], nil
+}
+
+func TestRotateSwapsPoolOnlyWhenSecretChanges(t *testing.T) {
+	t.Setenv("DB_ROTATE_DRAIN_GRACE", "1ms")
+
+	driverName := registerFakeSwapDriver(t)
+	ctx := context.Background()
+
+	initialPool, err := sql.Open(driverName, "initial")
+	if err != nil {
+		t.Fatalf("open initial pool: %v", err)
+	}
+	t.Cleanup(func() { initialPool.Close() })
+
+	db := &DB{Driver: "postgres"}
+	db.sqlDB.Store(initialPool)
+
+	secretA := DBSecret{Host: "a.db", Username: "u", Password: "p1"}
+	secretB := DBSecret{Host: "a.db", Username: "u", Password: "p2"}
+
+	provider := &stubSecretProvider{secrets: []DBSecret{secretA, secretA, secretB}}
+	r := &rotator{
+		db:         db,
+		provider:   provider,
+		driver:     stubDriver{name: driverName},
+		secretName: "test-secret",
+		stopCh:     make(chan struct{}),
+	}
+
+	// first check only seeds lastSecret - nothing to compare against yet
+	if err := r.rotate(ctx); err != nil {
+		t.Fatalf("rotate (seed): %v", err)
+	}
+	if db.DB() != initialPool {
+		t.Fatal("rotate swapped the pool on the very first (seeding) check")
+	}
+
+	// same secret again - still no swap
+	if err := r.rotate(ctx); err != nil {
+		t.Fatalf("rotate (unchanged): %v", err)
+	}
+	if db.DB() != initialPool {
+		t.Fatal("rotate swapped the pool when the secret hadn't changed")
+	}
+
+	// password changed - this check should swap in a new pool
+	if err := r.rotate(ctx); err != nil {
+		t.Fatalf("rotate (changed): %v", err)
+	}
+	if db.DB() == initialPool {
+		t.Fatal("rotate did not swap the pool after the secret changed")
+	}
+	t.Cleanup(func() { db.DB().Close() })
+}
+
+func TestRotateIgnoresHostlessChangesToUnrelatedFields(t *testing.T) {
+	t.Setenv("DB_ROTATE_DRAIN_GRACE", "1ms")
+
+	driverName := registerFakeSwapDriver(t)
+	ctx := context.Background()
+
+	initialPool, err := sql.Open(driverName, "initial")
+	if err != nil {
+		t.Fatalf("open initial pool: %v", err)
+	}
+	t.Cleanup(func() { initialPool.Close() })
+
+	db := &DB{Driver: "postgres"}
+	db.sqlDB.Store(initialPool)
+
+	// Database/Keyspace/Consistency aren't part of rotate's change check,
+	// only Username/Password/Host - so a change to just Database shouldn't
+	// trigger a swap.
+	secretA := DBSecret{Host: "a.db", Username: "u", Password: "p1", Database: "gym"}
+	secretA2 := DBSecret{Host: "a.db", Username: "u", Password: "p1", Database: "gym_v2"}
+
+	provider := &stubSecretProvider{secrets: []DBSecret{secretA, secretA2}}
+	r := &rotator{
+		db:         db,
+		provider:   provider,
+		driver:     stubDriver{name: driverName},
+		secretName: "test-secret",
+		stopCh:     make(chan struct{}),
+	}
+
+	if err := r.rotate(ctx); err != nil {
+		t.Fatalf("rotate (seed): %v", err)
+	}
+	if err := r.rotate(ctx); err != nil {
+		t.Fatalf("rotate (database-only change): %v", err)
+	}
+	if db.DB() != initialPool {
+		t.Fatal("rotate swapped the pool for a field it doesn't track")
+	}
+}
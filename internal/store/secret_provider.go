@@ -0,0 +1,364 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// [0.13] env vars read by NewSecretProviderFromEnv to assemble the chain
+const (
+	secretFilePathEnvVar = "DB_SECRET_FILE"
+
+	vaultAddrEnvVar     = "VAULT_ADDR"
+	vaultTokenEnvVar    = "VAULT_TOKEN"
+	vaultRoleIDEnvVar   = "VAULT_ROLE_ID"
+	vaultSecretIDEnvVar = "VAULT_SECRET_ID"
+
+	gcpProjectEnvVar = "GCP_PROJECT_ID"
+)
+
+// chainEntry pairs a SecretProvider with the cache behavior the chain
+// should apply to results coming from it.
+type chainEntry struct {
+	provider         SecretProvider
+	cacheTTL         time.Duration
+	negativeCacheTTL time.Duration
+}
+
+type chainCacheKey struct {
+	provider string
+	name     string
+}
+
+type chainCacheValue struct {
+	secret    DBSecret
+	version   string
+	err       error
+	expiresAt time.Time
+}
+
+// ChainSecretProvider tries its providers in order and returns the first
+// hit, caching both successful and failed lookups per (provider, name) so a
+// provider that's down doesn't get hammered on every Open.
+type ChainSecretProvider struct {
+	entries []chainEntry
+
+	cacheMu sync.RWMutex
+	cache   map[chainCacheKey]chainCacheValue
+}
+
+// NewChainSecretProvider builds an empty ChainSecretProvider. Providers are
+// appended with Add, each with its own cacheTTL for hits and
+// negativeCacheTTL for misses (0 disables that cache).
+func NewChainSecretProvider() *ChainSecretProvider {
+	return &ChainSecretProvider{
+		cache: make(map[chainCacheKey]chainCacheValue),
+	}
+}
+
+// Add appends a provider to the end of the chain with its own cache
+// behavior. Providers are tried in the order they're added.
+func (c *ChainSecretProvider) Add(name string, provider SecretProvider, cacheTTL, negativeCacheTTL time.Duration) *ChainSecretProvider {
+	c.entries = append(c.entries, chainEntry{
+		provider:         namedSecretProvider{name: name, SecretProvider: provider},
+		cacheTTL:         cacheTTL,
+		negativeCacheTTL: negativeCacheTTL,
+	})
+	return c
+}
+
+func (c *ChainSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	secret, _, err := c.getSecret(ctx, name)
+	return secret, err
+}
+
+// GetSecretVersion satisfies versionedSecretProvider so the rotator's type
+// assertion succeeds against the chain itself, not just the awsSecretProvider
+// buried inside it - without this, every version the rotator logs is "".
+func (c *ChainSecretProvider) GetSecretVersion(ctx context.Context, name string) (DBSecret, string, error) {
+	return c.getSecret(ctx, name)
+}
+
+func (c *ChainSecretProvider) getSecret(ctx context.Context, name string) (DBSecret, string, error) {
+	var lastErr error
+
+	for _, entry := range c.entries {
+		named := entry.provider.(namedSecretProvider)
+		key := chainCacheKey{provider: named.name, name: name}
+
+		if cached, ok := c.loadCached(key); ok {
+			if cached.err != nil {
+				lastErr = cached.err
+				continue
+			}
+			return cached.secret, cached.version, nil
+		}
+
+		secret, version, err := fetchSecretAndVersion(ctx, named.SecretProvider, name)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", named.name, err)
+			c.storeCached(key, chainCacheValue{err: lastErr}, entry.negativeCacheTTL)
+			continue
+		}
+
+		c.storeCached(key, chainCacheValue{secret: secret, version: version}, entry.cacheTTL)
+		return secret, version, nil
+	}
+
+	if lastErr == nil {
+		return DBSecret{}, "", fmt.Errorf("no secret providers configured")
+	}
+	return DBSecret{}, "", fmt.Errorf("all secret providers failed, last error: %w", lastErr)
+}
+
+// fetchSecretAndVersion calls GetSecretVersion when the underlying provider
+// (unwrapped from namedSecretProvider) supports it, so a real version like
+// AWS's survives being wrapped in the chain instead of always coming back
+// empty.
+func fetchSecretAndVersion(ctx context.Context, provider SecretProvider, name string) (DBSecret, string, error) {
+	if vp, ok := provider.(versionedSecretProvider); ok {
+		return vp.GetSecretVersion(ctx, name)
+	}
+	secret, err := provider.GetSecret(ctx, name)
+	return secret, "", err
+}
+
+func (c *ChainSecretProvider) loadCached(key chainCacheKey) (chainCacheValue, bool) {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+
+	entry, ok := c.cache[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return chainCacheValue{}, false
+	}
+	return entry, true
+}
+
+func (c *ChainSecretProvider) storeCached(key chainCacheKey, value chainCacheValue, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	value.expiresAt = time.Now().Add(ttl)
+
+	c.cacheMu.Lock()
+	c.cache[key] = value
+	c.cacheMu.Unlock()
+}
+
+// namedSecretProvider tags a SecretProvider with the name ChainSecretProvider
+// uses as part of its cache key, without requiring every provider
+// implementation to carry its own name.
+type namedSecretProvider struct {
+	name string
+	SecretProvider
+}
+
+// awsSecretProvider fetches secrets from AWS Secrets Manager. It used to be
+// the sole, hardcoded SecretProvider; it's now one link in the chain built
+// by NewSecretProviderFromEnv.
+type awsSecretProvider struct{}
+
+func (awsSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	return fetchDBSecret(ctx, name)
+}
+
+// GetSecretVersion additionally reports the AWS secret version ID, so the
+// rotator can log which version a swap came from.
+func (awsSecretProvider) GetSecretVersion(ctx context.Context, name string) (DBSecret, string, error) {
+	return fetchDBSecretWithVersion(ctx, name)
+}
+
+// EnvSecretProvider reads DBSecret fields directly from individual env
+// vars, so a shell/CI environment can configure the database without a
+// JSON payload or any of the other providers.
+type EnvSecretProvider struct{}
+
+func (EnvSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		return DBSecret{}, fmt.Errorf("DB_HOST not set")
+	}
+
+	return DBSecret{
+		Host:        host,
+		Port:        getenvDefault("DB_PORT", "5432"),
+		Database:    os.Getenv("DB_NAME"),
+		Username:    os.Getenv("DB_USERNAME"),
+		Password:    os.Getenv("DB_PASSWORD"),
+		Keyspace:    os.Getenv("DB_KEYSPACE"),
+		Consistency: os.Getenv("DB_CONSISTENCY"),
+	}, nil
+}
+
+// FileSecretProvider reads a DBSecret as JSON from a local file, so
+// engineers can run integration tests without AWS/Vault/GCP credentials.
+type FileSecretProvider struct {
+	Path string
+}
+
+func (f FileSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	raw, err := os.ReadFile(f.Path)
+	if err != nil {
+		return DBSecret{}, fmt.Errorf("read secret file %s: %w", f.Path, err)
+	}
+
+	// [secret file layout] either a single DBSecret, or a map of secret
+	// name -> DBSecret so one file can stand in for several secrets
+	var byName map[string]DBSecret
+	if err := json.Unmarshal(raw, &byName); err == nil {
+		if secret, ok := byName[name]; ok {
+			return secret, nil
+		}
+	}
+
+	var secret DBSecret
+	if err := json.Unmarshal(raw, &secret); err != nil {
+		return DBSecret{}, fmt.Errorf("decode secret file %s: %w", f.Path, err)
+	}
+	return secret, nil
+}
+
+// VaultSecretProvider reads a DBSecret from a HashiCorp Vault KV v2 mount
+// at secret/data/<path>, authenticating with either a static token or
+// AppRole credentials.
+type VaultSecretProvider struct {
+	Addr     string
+	Token    string
+	RoleID   string
+	SecretID string
+
+	httpClient *http.Client
+	loginOnce  sync.Once
+	loginErr   error
+}
+
+func (v *VaultSecretProvider) client() *http.Client {
+	if v.httpClient == nil {
+		v.httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	return v.httpClient
+}
+
+func (v *VaultSecretProvider) token(ctx context.Context) (string, error) {
+	if v.Token != "" {
+		return v.Token, nil
+	}
+
+	v.loginOnce.Do(func() {
+		v.Token, v.loginErr = v.approleLogin(ctx)
+	})
+	return v.Token, v.loginErr
+}
+
+func (v *VaultSecretProvider) approleLogin(ctx context.Context) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"role_id":   v.RoleID,
+		"secret_id": v.SecretID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.Addr+"/v1/auth/approle/login", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault approle login: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault approle login: unexpected status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode vault login response: %w", err)
+	}
+
+	return out.Auth.ClientToken, nil
+}
+
+func (v *VaultSecretProvider) GetSecret(ctx context.Context, name string) (DBSecret, error) {
+	token, err := v.token(ctx)
+	if err != nil {
+		return DBSecret{}, err
+	}
+
+	path := strings.TrimPrefix(name, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/secret/data/%s", v.Addr, path), nil)
+	if err != nil {
+		return DBSecret{}, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := v.client().Do(req)
+	if err != nil {
+		return DBSecret{}, fmt.Errorf("vault read %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(resp.Body)
+		return DBSecret{}, fmt.Errorf("vault read %s: unexpected status %d: %s", path, resp.StatusCode, raw)
+	}
+
+	var out struct {
+		Data struct {
+			Data DBSecret `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return DBSecret{}, fmt.Errorf("decode vault response for %s: %w", path, err)
+	}
+
+	return out.Data.Data, nil
+}
+
+// NewSecretProviderFromEnv builds the SecretProvider Open uses, chaining
+// together whichever of env/file/Vault/AWS/GCP are configured. The env and
+// AWS providers are always added (env fails at lookup time when DB_HOST is
+// unset); file, Vault and GCP are added only when their env var is set.
+func NewSecretProviderFromEnv() SecretProvider {
+	chain := NewChainSecretProvider()
+
+	chain.Add("env", EnvSecretProvider{}, 0, 0)
+
+	if path := strings.TrimSpace(os.Getenv(secretFilePathEnvVar)); path != "" {
+		chain.Add("file", FileSecretProvider{Path: path}, 0, 0)
+	}
+
+	if addr := strings.TrimSpace(os.Getenv(vaultAddrEnvVar)); addr != "" {
+		chain.Add("vault", &VaultSecretProvider{
+			Addr:     addr,
+			Token:    os.Getenv(vaultTokenEnvVar),
+			RoleID:   os.Getenv(vaultRoleIDEnvVar),
+			SecretID: os.Getenv(vaultSecretIDEnvVar),
+		}, getSecretCacheTTL(), 30*time.Second)
+	}
+
+	chain.Add("aws-secrets-manager", awsSecretProvider{}, getSecretCacheTTL(), 30*time.Second)
+
+	if project := strings.TrimSpace(os.Getenv(gcpProjectEnvVar)); project != "" {
+		chain.Add("gcp-secret-manager", gcpSecretProvider{project: project}, getSecretCacheTTL(), 30*time.Second)
+	}
+
+	return chain
+}